@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// AADFunc derives extra context to bind into the AEAD tag of every
+// ciphertext an Encrypter produces, on top of the deadline this package
+// always binds by default. Typical uses are a session token, a hash of the
+// User-Agent header, the remote IP subnet, or an application name —
+// context that isn't already part of the session values being encrypted,
+// but that a ciphertext should not be valid without.
+//
+// Encode calls fn with the deadline and values being encoded. Decode calls
+// fn with the deadline recovered from the ciphertext's clear-text deadline
+// header and a nil values map, since the session values aren't known until
+// the ciphertext has been authenticated; write fn so its output does not
+// depend on values unless the Encrypter is only ever used to Encode.
+type AADFunc func(deadline time.Time, values map[string]interface{}) []byte
+
+// Option configures an Encrypter at construction time.
+type Option func(*Encrypter)
+
+// WithAADFunc binds the bytes fn returns into the AEAD's additional
+// authenticated data on every Encode and Decode call, on top of the
+// deadline this package always binds by default. The same fn must be
+// configured on the Encrypter used to Decode a ciphertext as was used to
+// Encode it, or Decode fails.
+func WithAADFunc(fn AADFunc) Option {
+	return func(e *Encrypter) {
+		e.aadFunc = fn
+	}
+}
+
+// deadlineAADLen is the length, in bytes, of the clear-text deadline header
+// every ciphertext carries ahead of its nonce.
+const deadlineAADLen = 8
+
+// encodeDeadlineHeader serializes deadline as an 8-byte, big-endian Unix
+// timestamp (seconds). The header is stored unencrypted so Decode can bind
+// it into the AEAD's additional authenticated data before calling Open,
+// without having to trust anything about the ciphertext first, preventing
+// a ciphertext from being swapped for another one carrying a longer-lived
+// deadline. This layout is part of the on-wire format and must not change
+// without a version bump.
+func encodeDeadlineHeader(deadline time.Time) []byte {
+	header := make([]byte, deadlineAADLen)
+	binary.BigEndian.PutUint64(header, uint64(deadline.Unix()))
+	return header
+}
+
+// decodeDeadlineHeader parses the header written by encodeDeadlineHeader.
+// The caller must ensure header is at least deadlineAADLen bytes long.
+func decodeDeadlineHeader(header []byte) time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint64(header)), 0).UTC()
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEncrypter_WithAADFunc(t *testing.T) {
+	contextAAD := func(time.Time, map[string]interface{}) []byte {
+		return []byte("session-token:abc123")
+	}
+
+	t.Run("round-trips with matching aad func", func(t *testing.T) {
+		encrypter, err := NewAESGCM(testKey256, WithAADFunc(contextAAD))
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		now := time.Now()
+		ciphertext, err := encrypter.Encode(now, map[string]interface{}{"string": "test"})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+		plaintime, plaindata, err := encrypter.Decode(ciphertext)
+		if err != nil {
+			t.Fatalf("decryption failed: %s", err)
+		}
+		if !plaintime.Equal(now) {
+			t.Errorf("expected plaintime to be %s, got %s", now, plaintime)
+		}
+		if plaindata["string"] != "test" {
+			t.Errorf("expected decrypted string to be test, got %v", plaindata["string"])
+		}
+	})
+
+	t.Run("fails to decode without the matching aad func", func(t *testing.T) {
+		withAAD, err := NewAESGCM(testKey256, WithAADFunc(contextAAD))
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := withAAD.Encode(time.Now(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+
+		withoutAAD, err := NewAESGCM(testKey256)
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		if _, _, err = withoutAAD.Decode(ciphertext); err == nil {
+			t.Errorf("expected decryption to fail without the same aad func")
+		}
+	})
+
+	t.Run("fails to decode with a different aad func", func(t *testing.T) {
+		withAAD, err := NewAESGCM(testKey256, WithAADFunc(contextAAD))
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := withAAD.Encode(time.Now(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+
+		otherAAD := func(time.Time, map[string]interface{}) []byte {
+			return []byte("session-token:different")
+		}
+		otherEncrypter, err := NewAESGCM(testKey256, WithAADFunc(otherAAD))
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		if _, _, err = otherEncrypter.Decode(ciphertext); err == nil {
+			t.Errorf("expected decryption to fail with a mismatched aad func")
+		}
+	})
+
+	t.Run("rejects a tampered deadline header", func(t *testing.T) {
+		encrypter, err := NewAESGCM(testKey256)
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := encrypter.Encode(time.Now(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+		tampered := append([]byte(nil), ciphertext...)
+		tampered[deadlineAADLen-1] ^= 0xff
+
+		if _, _, err = encrypter.Decode(tampered); err == nil {
+			t.Errorf("expected decryption to fail with a tampered deadline header")
+		}
+	})
+
+	t.Run("fails with too short ciphertext", func(t *testing.T) {
+		encrypter, err := NewAESGCM(testKey256)
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		_, err = encrypter.decrypt([]byte{0x01, 0x02})
+		if !errors.Is(err, ErrCiphertextTooShort) {
+			t.Errorf("expected ErrCiphertextTooShort, got %s", err)
+		}
+	})
+}
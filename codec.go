@@ -0,0 +1,194 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// ValueCodec marshals and unmarshals the deadline and values that Encode
+// and Decode operate on. Swapping the ValueCodec an Encrypter uses changes
+// only how session data is serialized before encryption; it has no effect
+// on the AEAD cipher or key used to encrypt it.
+type ValueCodec interface {
+	// Marshal serializes deadline and values into bytes ready to be
+	// encrypted.
+	Marshal(deadline time.Time, values map[string]interface{}) ([]byte, error)
+
+	// Unmarshal restores the deadline and values previously produced by
+	// Marshal.
+	Unmarshal(data []byte) (time.Time, map[string]interface{}, error)
+}
+
+// valueCodecPayload is the struct every built-in ValueCodec serializes the
+// deadline and values through.
+type valueCodecPayload struct {
+	Deadline time.Time
+	Values   map[string]interface{}
+}
+
+// GobValueCodec serializes session data with encoding/gob. It is this
+// package's default ValueCodec. Values stored under custom types must be
+// registered with gob.Register, or Encode fails; JSONValueCodec or
+// CBORValueCodec avoid that requirement.
+type GobValueCodec struct{}
+
+// Marshal implements the ValueCodec interface for GobValueCodec.
+func (GobValueCodec) Marshal(deadline time.Time, values map[string]interface{}) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	payload := valueCodecPayload{Deadline: deadline, Values: values}
+	if err := gob.NewEncoder(buffer).Encode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to encode session data as gob: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Unmarshal implements the ValueCodec interface for GobValueCodec.
+func (GobValueCodec) Unmarshal(data []byte) (time.Time, map[string]interface{}, error) {
+	var payload valueCodecPayload
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&payload); err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to decode session data as gob: %w", err)
+	}
+	return payload.Deadline, payload.Values, nil
+}
+
+// JSONValueCodec serializes session data as JSON, so it can be read by
+// non-Go clients without gob's type-registration requirement. Values that
+// round-trip through it come back with JSON's types, e.g. as float64
+// rather than their original Go numeric type.
+type JSONValueCodec struct{}
+
+// Marshal implements the ValueCodec interface for JSONValueCodec.
+func (JSONValueCodec) Marshal(deadline time.Time, values map[string]interface{}) ([]byte, error) {
+	payload := valueCodecPayload{Deadline: deadline, Values: values}
+	data, err := json.Marshal(&payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session data as json: %w", err)
+	}
+	return data, nil
+}
+
+// Unmarshal implements the ValueCodec interface for JSONValueCodec.
+func (JSONValueCodec) Unmarshal(data []byte) (time.Time, map[string]interface{}, error) {
+	var payload valueCodecPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to decode session data as json: %w", err)
+	}
+	return payload.Deadline, payload.Values, nil
+}
+
+// CBORValueCodec serializes session data as CBOR (RFC 8949), a compact
+// binary alternative to JSON with the same cross-language interoperability
+// benefits and a smaller wire size.
+type CBORValueCodec struct{}
+
+// cborEncMode encodes time.Time with nanosecond precision (cbor's default
+// TimeUnix mode truncates to whole seconds), so a session deadline
+// round-trips through CBORValueCodec exactly as it does through
+// GobValueCodec and JSONValueCodec.
+var cborEncMode = func() cbor.EncMode {
+	mode, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	if err != nil {
+		panic(fmt.Sprintf("scscrypter: failed to build cbor encode mode: %s", err))
+	}
+	return mode
+}()
+
+// Marshal implements the ValueCodec interface for CBORValueCodec.
+func (CBORValueCodec) Marshal(deadline time.Time, values map[string]interface{}) ([]byte, error) {
+	payload := valueCodecPayload{Deadline: deadline, Values: values}
+	data, err := cborEncMode.Marshal(&payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session data as cbor: %w", err)
+	}
+	return data, nil
+}
+
+// Unmarshal implements the ValueCodec interface for CBORValueCodec.
+func (CBORValueCodec) Unmarshal(data []byte) (time.Time, map[string]interface{}, error) {
+	var payload valueCodecPayload
+	if err := cbor.Unmarshal(data, &payload); err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to decode session data as cbor: %w", err)
+	}
+	return payload.Deadline, payload.Values, nil
+}
+
+// codecTag is the 1-byte tag Encode prefixes the serialized payload with,
+// so Decode can select the matching built-in ValueCodec without requiring
+// the Encrypter that decodes a ciphertext to be configured with the same
+// ValueCodec as the one that encoded it.
+type codecTag byte
+
+const (
+	codecTagGob codecTag = iota
+	codecTagJSON
+	codecTagCBOR
+)
+
+// ErrUnknownValueCodec indicates that a ciphertext's codec tag does not
+// match any of this package's built-in ValueCodecs.
+var ErrUnknownValueCodec = errors.New("unknown value codec")
+
+// tagForCodec returns the codecTag to prefix codec's output with. codec
+// must be one of this package's built-in ValueCodec implementations.
+func tagForCodec(codec ValueCodec) (codecTag, error) {
+	switch codec.(type) {
+	case GobValueCodec:
+		return codecTagGob, nil
+	case JSONValueCodec:
+		return codecTagJSON, nil
+	case CBORValueCodec:
+		return codecTagCBOR, nil
+	default:
+		return 0, fmt.Errorf("scscrypter: %T is not one of the built-in ValueCodec implementations", codec)
+	}
+}
+
+// codecForTag returns the built-in ValueCodec a ciphertext's codec tag
+// selects.
+func codecForTag(tag codecTag) (ValueCodec, error) {
+	switch tag {
+	case codecTagGob:
+		return GobValueCodec{}, nil
+	case codecTagJSON:
+		return JSONValueCodec{}, nil
+	case codecTagCBOR:
+		return CBORValueCodec{}, nil
+	default:
+		return nil, ErrUnknownValueCodec
+	}
+}
+
+// NewWithCodec creates a new Encrypter instance using the provided AEAD
+// cipher, with session data serialized through codec instead of this
+// package's default, GobValueCodec.
+//
+// Parameters:
+//   - aead cipher.AEAD: The AEAD cipher used for encryption and decryption.
+//     This cipher must be properly initialized before calling NewWithCodec.
+//   - codec ValueCodec: The codec Encode and Decode serialize session data
+//     through. Must be one of GobValueCodec, JSONValueCodec or
+//     CBORValueCodec.
+//   - opts ...Option: Optional settings, such as WithAADFunc.
+//
+// Returns:
+//   - Encrypter: An Encrypter instance configured with aead and codec.
+//   - error: An error if codec is not one of the built-in ValueCodecs.
+func NewWithCodec(aead cipher.AEAD, codec ValueCodec, opts ...Option) (Encrypter, error) {
+	if _, err := tagForCodec(codec); err != nil {
+		return Encrypter{}, err
+	}
+	encrypter := New(aead, opts...)
+	encrypter.codec = codec
+	return encrypter, nil
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEncrypter_NewWithCodec(t *testing.T) {
+	codecs := []struct {
+		name  string
+		codec ValueCodec
+	}{
+		{"Gob", GobValueCodec{}},
+		{"JSON", JSONValueCodec{}},
+		{"CBOR", CBORValueCodec{}},
+	}
+	for _, tt := range codecs {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypter, err := NewWithCodec(newTestAEAD(t), tt.codec)
+			if err != nil {
+				t.Fatalf("failed to create encrypter: %s", err)
+			}
+			now := time.Now()
+			ciphertext, err := encrypter.Encode(now, map[string]interface{}{"string": "test"})
+			if err != nil {
+				t.Fatalf("encryption failed: %s", err)
+			}
+			plaintime, plaindata, err := encrypter.Decode(ciphertext)
+			if err != nil {
+				t.Fatalf("decryption failed: %s", err)
+			}
+			if !plaintime.Equal(now) {
+				t.Errorf("expected plaintime to be %s, got %s", now, plaintime)
+			}
+			if plaindata["string"] != "test" {
+				t.Errorf("expected decrypted string to be test, got %v", plaindata["string"])
+			}
+		})
+	}
+
+	t.Run("rejects a non-built-in ValueCodec", func(t *testing.T) {
+		_, err := NewWithCodec(newTestAEAD(t), customValueCodec{})
+		if err == nil {
+			t.Errorf("expected NewWithCodec to reject a non-built-in ValueCodec")
+		}
+	})
+
+	t.Run("defaults to GobValueCodec", func(t *testing.T) {
+		encrypter, err := NewAESGCM(testKey256)
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := encrypter.Encode(time.Now(), map[string]interface{}{"n": 1})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+		data, err := encrypter.decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("decryption failed: %s", err)
+		}
+		// data[0] is the compression tag; the codec tag follows it.
+		if codecTag(data[1]) != codecTagGob {
+			t.Errorf("expected default codec tag to be codecTagGob, got %d", data[1])
+		}
+	})
+
+	t.Run("decodes regardless of the decoding Encrypter's configured codec", func(t *testing.T) {
+		aead := newTestAEAD(t)
+		jsonEncrypter, err := NewWithCodec(aead, JSONValueCodec{})
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := jsonEncrypter.Encode(time.Now(), map[string]interface{}{"string": "test"})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+
+		gobEncrypter, err := NewWithCodec(aead, GobValueCodec{})
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		if _, _, err = gobEncrypter.Decode(ciphertext); err != nil {
+			t.Errorf("expected decode to auto-select the JSON codec via its tag, got: %s", err)
+		}
+	})
+
+	t.Run("fails to decode with an unknown codec tag", func(t *testing.T) {
+		encrypter, err := NewAESGCM(testKey256)
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		_, err = codecForTag(codecTag(0xff))
+		if !errors.Is(err, ErrUnknownValueCodec) {
+			t.Errorf("expected ErrUnknownValueCodec, got %s", err)
+		}
+		_ = encrypter
+	})
+}
+
+// customValueCodec is a ValueCodec implementation outside this package's
+// built-ins, used to test that NewWithCodec rejects it.
+type customValueCodec struct{}
+
+func (customValueCodec) Marshal(time.Time, map[string]interface{}) ([]byte, error) { return nil, nil }
+func (customValueCodec) Unmarshal([]byte) (time.Time, map[string]interface{}, error) {
+	return time.Time{}, nil, nil
+}
+
+func newTestAEAD(t *testing.T) cipher.AEAD {
+	t.Helper()
+	block, err := aes.NewCipher(testKey256)
+	if err != nil {
+		t.Fatalf("failed to create AES cipher: %s", err)
+	}
+	aead, err := cipher.NewGCMWithRandomNonce(block)
+	if err != nil {
+		t.Fatalf("failed to create AES-GCM AEAD: %s", err)
+	}
+	return aead
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionAlgo selects the algorithm WithCompression compresses session
+// data with before encryption.
+type CompressionAlgo int
+
+const (
+	// CompressionZstd compresses with zstd, which typically compresses
+	// better than gzip at a comparable speed. It is the recommended choice
+	// for most session payloads.
+	CompressionZstd CompressionAlgo = iota + 1
+
+	// CompressionGzip compresses with gzip, a lighter-weight alternative to
+	// zstd with a smaller dependency footprint.
+	CompressionGzip
+)
+
+// WithCompression compresses the plaintext session data with algo before
+// encryption, and transparently decompresses it again on Decode via a
+// 1-byte algorithm tag prefixed to the plaintext. level is passed through
+// to the chosen algorithm: for CompressionZstd it is a zstd compression
+// level (see zstd.EncoderLevelFromZstd), and for CompressionGzip it is a
+// gzip level as accepted by compress/gzip.NewWriterLevel. If compressing a
+// given payload would not make it smaller, Encode stores it uncompressed
+// instead, so WithCompression never makes a ciphertext larger than it
+// would otherwise be.
+func WithCompression(algo CompressionAlgo, level int) Option {
+	return func(e *Encrypter) {
+		e.compressionAlgo = algo
+		e.compressionLevel = level
+	}
+}
+
+// compressionTag is the 1-byte tag Encode prefixes the plaintext with,
+// identifying which algorithm, if any, compressed it.
+type compressionTag byte
+
+const (
+	compressionTagNone compressionTag = iota
+	compressionTagZstd
+	compressionTagGzip
+)
+
+// compress compresses data with algo at level, returning the compressionTag
+// to prefix it with. If compressing data does not make it smaller, compress
+// returns it unmodified with compressionTagNone.
+func compress(algo CompressionAlgo, level int, data []byte) (compressionTag, []byte, error) {
+	var tag compressionTag
+	var compressed []byte
+	var err error
+
+	switch algo {
+	case CompressionZstd:
+		tag = compressionTagZstd
+		compressed, err = compressZstd(level, data)
+	case CompressionGzip:
+		tag = compressionTagGzip
+		compressed, err = compressGzip(level, data)
+	default:
+		return compressionTagNone, data, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(compressed) >= len(data) {
+		return compressionTagNone, data, nil
+	}
+	return tag, compressed, nil
+}
+
+// decompress reverses compress, selecting the algorithm via tag.
+func decompress(tag compressionTag, data []byte) ([]byte, error) {
+	switch tag {
+	case compressionTagNone:
+		return data, nil
+	case compressionTagZstd:
+		return decompressZstd(data)
+	case compressionTagGzip:
+		return decompressGzip(data)
+	default:
+		return nil, fmt.Errorf("scscrypter: unknown compression tag %d", tag)
+	}
+}
+
+func compressZstd(level int, data []byte) ([]byte, error) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func decompressZstd(data []byte) ([]byte, error) {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	defer decoder.Close()
+	decoded, err := decoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress zstd payload: %w", err)
+	}
+	return decoded, nil
+}
+
+func compressGzip(level int, data []byte) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	writer, err := gzip.NewWriterLevel(buffer, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	if _, err = writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write gzip payload: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+func decompressGzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer reader.Close()
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress gzip payload: %w", err)
+	}
+	return decoded, nil
+}
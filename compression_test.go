@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncrypter_WithCompression(t *testing.T) {
+	algos := []struct {
+		name  string
+		algo  CompressionAlgo
+		level int
+	}{
+		{"zstd", CompressionZstd, 0},
+		{"gzip", CompressionGzip, gzip.DefaultCompression},
+	}
+	repetitive := strings.Repeat("session-value-", 256)
+
+	for _, tt := range algos {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypter, err := NewAESGCM(testKey256, WithCompression(tt.algo, tt.level))
+			if err != nil {
+				t.Fatalf("failed to create encrypter: %s", err)
+			}
+			now := time.Now()
+			ciphertext, err := encrypter.Encode(now, map[string]interface{}{"data": repetitive})
+			if err != nil {
+				t.Fatalf("encryption failed: %s", err)
+			}
+			plaintime, plaindata, err := encrypter.Decode(ciphertext)
+			if err != nil {
+				t.Fatalf("decryption failed: %s", err)
+			}
+			if !plaintime.Equal(now) {
+				t.Errorf("expected plaintime to be %s, got %s", now, plaintime)
+			}
+			if plaindata["data"] != repetitive {
+				t.Errorf("decrypted data does not match original payload")
+			}
+
+			uncompressed, err := NewAESGCM(testKey256)
+			if err != nil {
+				t.Fatalf("failed to create encrypter: %s", err)
+			}
+			plainCiphertext, err := uncompressed.Encode(now, map[string]interface{}{"data": repetitive})
+			if err != nil {
+				t.Fatalf("encryption failed: %s", err)
+			}
+			if len(ciphertext) >= len(plainCiphertext) {
+				t.Errorf("expected compression to shrink a repetitive payload: compressed=%d uncompressed=%d", len(ciphertext), len(plainCiphertext))
+			}
+		})
+	}
+
+	t.Run("falls back to uncompressed when compression would not shrink the payload", func(t *testing.T) {
+		encrypter, err := NewAESGCM(testKey256, WithCompression(CompressionZstd, 0))
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := encrypter.Encode(time.Now(), map[string]interface{}{"n": 1})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+		data, err := encrypter.decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("decryption failed: %s", err)
+		}
+		if compressionTag(data[0]) != compressionTagNone {
+			t.Errorf("expected compression tag to be compressionTagNone for a payload that does not shrink, got %d", data[0])
+		}
+	})
+
+	t.Run("defaults to no compression", func(t *testing.T) {
+		encrypter, err := NewAESGCM(testKey256)
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := encrypter.Encode(time.Now(), map[string]interface{}{"n": 1})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+		data, err := encrypter.decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("decryption failed: %s", err)
+		}
+		if compressionTag(data[0]) != compressionTagNone {
+			t.Errorf("expected compression tag to be compressionTagNone by default, got %d", data[0])
+		}
+	})
+
+	t.Run("fails to decompress an unknown compression tag", func(t *testing.T) {
+		_, err := decompress(compressionTag(0xff), []byte("data"))
+		if err == nil {
+			t.Errorf("expected decompress to fail with an unknown compression tag")
+		}
+	})
+}
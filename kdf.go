@@ -0,0 +1,402 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDF identifies a password-hashing key derivation function used to turn a
+// user-supplied passphrase into an AEAD key.
+type KDF uint8
+
+const (
+	// KDFArgon2id derives the key using Argon2id, the winner of the Password
+	// Hashing Competition. It is the default KDF used by the *FromPassphrase
+	// constructors.
+	KDFArgon2id KDF = iota
+
+	// KDFScrypt derives the key using scrypt.
+	KDFScrypt
+
+	// KDFPBKDF2SHA256 derives the key using PBKDF2 with HMAC-SHA256.
+	KDFPBKDF2SHA256
+)
+
+const (
+	// kdfSaltSize is the length, in bytes, of the random salt generated for
+	// each passphrase-derived key.
+	kdfSaltSize = 16
+
+	// minKDFKeyLen is the minimum accepted derived key length, in bytes.
+	minKDFKeyLen = 16
+
+	// Minimum accepted Argon2id parameters, roughly following the OWASP
+	// password storage cheat sheet recommendations.
+	minArgon2Time      = 1
+	minArgon2MemoryKiB = 19 * 1024
+	minArgon2Threads   = 1
+
+	// Minimum accepted scrypt parameters.
+	minScryptN = 1 << 14
+	minScryptR = 8
+	minScryptP = 1
+
+	// Minimum accepted PBKDF2-SHA256 iteration count.
+	minPBKDF2Iterations = 210_000
+
+	// maxKDFKeyLen is the maximum accepted derived key length, in bytes.
+	maxKDFKeyLen = 128
+
+	// Maximum accepted Argon2id parameters. These bound the memory and CPU
+	// time a ciphertext can force a caller to spend deriving a key, since
+	// the KDF header is clear-text and attacker-controlled: decrypt must
+	// reject out-of-range values before calling deriveKey, not just
+	// newFromPassphrase at construction time. Kept close to a sane
+	// production ceiling rather than the theoretical maximum a legitimate
+	// caller might configure, since every decrypt of an untrusted
+	// ciphertext pays this cost before the AEAD tag is ever checked.
+	maxArgon2Time      = 10
+	maxArgon2MemoryKiB = 512 * 1024
+	maxArgon2Threads   = 16
+
+	// Maximum accepted scrypt parameters. N*R is the dominant factor in
+	// scrypt's ~128*N*R byte memory footprint, so both are capped well
+	// below what the type alone would allow.
+	maxScryptN = 1 << 18
+	maxScryptR = 16
+	maxScryptP = 16
+
+	// Maximum accepted PBKDF2-SHA256 iteration count.
+	maxPBKDF2Iterations = 2_000_000
+)
+
+// ErrWeakKDFParams indicates that the supplied KDFParams fall below the
+// minimum parameters this package considers safe for deriving an AEAD key
+// from a passphrase, or exceed the maximum parameters it accepts.
+var ErrWeakKDFParams = errors.New("kdf parameters are weaker than the minimum allowed")
+
+// ErrInvalidKDFHeader indicates that a ciphertext produced by a
+// passphrase-based Encrypter is missing its KDF header, or the header is
+// malformed or from an unsupported version.
+var ErrInvalidKDFHeader = errors.New("invalid or missing kdf header")
+
+// KDFParams holds the tunable cost parameters for the KDF selected by the
+// KDF field. Only the fields relevant to the selected KDF are used; the
+// others are ignored. A zero-value KDFParams selects Argon2id with this
+// package's default parameters.
+type KDFParams struct {
+	// KDF selects the key derivation function. The zero value is KDFArgon2id.
+	KDF KDF
+
+	// Time is the Argon2id time cost (number of iterations).
+	Time uint32
+
+	// Memory is the Argon2id memory cost in KiB.
+	Memory uint32
+
+	// Threads is the Argon2id degree of parallelism.
+	Threads uint8
+
+	// N is the scrypt CPU/memory cost parameter. It must be a power of two.
+	N int
+
+	// R is the scrypt block size parameter.
+	R int
+
+	// P is the scrypt parallelization parameter.
+	P int
+
+	// Iterations is the PBKDF2-SHA256 iteration count.
+	Iterations int
+
+	// KeyLen is the length, in bytes, of the derived key. It defaults to 32
+	// (the key length required by AES-256, ChaCha20-Poly1305 and
+	// XChaCha20-Poly1305).
+	KeyLen uint32
+}
+
+// DefaultKDFParams returns this package's recommended cost parameters for
+// the given KDF.
+func DefaultKDFParams(kdf KDF) KDFParams {
+	switch kdf {
+	case KDFScrypt:
+		return KDFParams{KDF: KDFScrypt, N: 1 << 15, R: 8, P: 1, KeyLen: 32}
+	case KDFPBKDF2SHA256:
+		return KDFParams{KDF: KDFPBKDF2SHA256, Iterations: 600_000, KeyLen: 32}
+	default:
+		return KDFParams{KDF: KDFArgon2id, Time: 3, Memory: 64 * 1024, Threads: 4, KeyLen: 32}
+	}
+}
+
+// withKDFDefaults fills any zero-valued fields relevant to params.KDF with
+// this package's default parameters.
+func withKDFDefaults(params KDFParams) KDFParams {
+	defaults := DefaultKDFParams(params.KDF)
+	if params.KeyLen == 0 {
+		params.KeyLen = defaults.KeyLen
+	}
+	switch params.KDF {
+	case KDFScrypt:
+		if params.N == 0 {
+			params.N = defaults.N
+		}
+		if params.R == 0 {
+			params.R = defaults.R
+		}
+		if params.P == 0 {
+			params.P = defaults.P
+		}
+	case KDFPBKDF2SHA256:
+		if params.Iterations == 0 {
+			params.Iterations = defaults.Iterations
+		}
+	default:
+		if params.Time == 0 {
+			params.Time = defaults.Time
+		}
+		if params.Memory == 0 {
+			params.Memory = defaults.Memory
+		}
+		if params.Threads == 0 {
+			params.Threads = defaults.Threads
+		}
+	}
+	return params
+}
+
+// validateKDFParams rejects parameters weaker than the minimums, or
+// stronger than the maximums, this package considers safe. The maximums
+// matter on the decrypt path: the KDF header is clear-text and
+// attacker-controlled, so without an upper bound a tampered ciphertext
+// could force an arbitrarily expensive key derivation before
+// authentication ever happens.
+func validateKDFParams(params KDFParams) error {
+	if params.KeyLen < minKDFKeyLen || params.KeyLen > maxKDFKeyLen {
+		return ErrWeakKDFParams
+	}
+	switch params.KDF {
+	case KDFScrypt:
+		if params.N < minScryptN || params.N > maxScryptN ||
+			params.R < minScryptR || params.R > maxScryptR ||
+			params.P < minScryptP || params.P > maxScryptP {
+			return ErrWeakKDFParams
+		}
+	case KDFPBKDF2SHA256:
+		if params.Iterations < minPBKDF2Iterations || params.Iterations > maxPBKDF2Iterations {
+			return ErrWeakKDFParams
+		}
+	case KDFArgon2id:
+		if params.Time < minArgon2Time || params.Time > maxArgon2Time ||
+			params.Memory < minArgon2MemoryKiB || params.Memory > maxArgon2MemoryKiB ||
+			params.Threads < minArgon2Threads || params.Threads > maxArgon2Threads {
+			return ErrWeakKDFParams
+		}
+	default:
+		return fmt.Errorf("unsupported kdf: %d", params.KDF)
+	}
+	return nil
+}
+
+// deriveKey derives an AEAD key of params.KeyLen bytes from passphrase and
+// salt using the KDF selected by params.KDF.
+func deriveKey(params KDFParams, passphrase, salt []byte) ([]byte, error) {
+	switch params.KDF {
+	case KDFScrypt:
+		key, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, int(params.KeyLen))
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key with scrypt: %w", err)
+		}
+		return key, nil
+	case KDFPBKDF2SHA256:
+		return pbkdf2.Key(passphrase, salt, params.Iterations, int(params.KeyLen), sha256.New), nil
+	default:
+		return argon2.IDKey(passphrase, salt, params.Time, params.Memory, params.Threads, params.KeyLen), nil
+	}
+}
+
+// kdfHeaderMagic identifies a ciphertext produced by a passphrase-based
+// Encrypter so Decode can locate and parse the KDF header prepended to it.
+var kdfHeaderMagic = [4]byte{'S', 'C', 'S', 'C'}
+
+// kdfHeaderVersion is the current on-wire version of the KDF header layout.
+const kdfHeaderVersion = 1
+
+// kdfHeaderParamsLen is the encoded length, in bytes, of a KDFParams value:
+// KDF(1) + Time(4) + Memory(4) + Threads(1) + N(4) + R(4) + P(4) +
+// Iterations(4) + KeyLen(4).
+const kdfHeaderParamsLen = 1 + 4 + 4 + 1 + 4 + 4 + 4 + 4 + 4
+
+// kdfHeaderLen is the total length, in bytes, of the header prepended to
+// every ciphertext produced by a passphrase-based Encrypter: magic(4) +
+// version(1) + params(kdfHeaderParamsLen) + salt(kdfSaltSize).
+const kdfHeaderLen = len(kdfHeaderMagic) + 1 + kdfHeaderParamsLen + kdfSaltSize
+
+// encodeKDFHeader serializes the versioned magic bytes, KDF id, cost
+// parameters and salt that Decode needs to re-derive the same key from the
+// passphrase, with no other coordination required.
+func encodeKDFHeader(params KDFParams, salt []byte) []byte {
+	header := make([]byte, 0, kdfHeaderLen)
+	header = append(header, kdfHeaderMagic[:]...)
+	header = append(header, kdfHeaderVersion)
+	header = append(header, byte(params.KDF))
+	header = binary.BigEndian.AppendUint32(header, params.Time)
+	header = binary.BigEndian.AppendUint32(header, params.Memory)
+	header = append(header, params.Threads)
+	header = binary.BigEndian.AppendUint32(header, uint32(params.N))
+	header = binary.BigEndian.AppendUint32(header, uint32(params.R))
+	header = binary.BigEndian.AppendUint32(header, uint32(params.P))
+	header = binary.BigEndian.AppendUint32(header, uint32(params.Iterations))
+	header = binary.BigEndian.AppendUint32(header, params.KeyLen)
+	header = append(header, salt...)
+	return header
+}
+
+// decodeKDFHeader parses the header written by encodeKDFHeader, returning
+// the KDF parameters, the salt and the remaining
+// (deadline header||nonce||ciphertext) bytes.
+func decodeKDFHeader(data []byte) (params KDFParams, salt, rest []byte, err error) {
+	if len(data) < kdfHeaderLen {
+		return KDFParams{}, nil, nil, ErrCiphertextTooShort
+	}
+	offset := 0
+	magic := data[offset : offset+len(kdfHeaderMagic)]
+	offset += len(kdfHeaderMagic)
+	for i, b := range kdfHeaderMagic {
+		if magic[i] != b {
+			return KDFParams{}, nil, nil, ErrInvalidKDFHeader
+		}
+	}
+	if data[offset] != kdfHeaderVersion {
+		return KDFParams{}, nil, nil, ErrInvalidKDFHeader
+	}
+	offset++
+
+	params.KDF = KDF(data[offset])
+	offset++
+	params.Time = binary.BigEndian.Uint32(data[offset:])
+	offset += 4
+	params.Memory = binary.BigEndian.Uint32(data[offset:])
+	offset += 4
+	params.Threads = data[offset]
+	offset++
+	params.N = int(binary.BigEndian.Uint32(data[offset:]))
+	offset += 4
+	params.R = int(binary.BigEndian.Uint32(data[offset:]))
+	offset += 4
+	params.P = int(binary.BigEndian.Uint32(data[offset:]))
+	offset += 4
+	params.Iterations = int(binary.BigEndian.Uint32(data[offset:]))
+	offset += 4
+	params.KeyLen = binary.BigEndian.Uint32(data[offset:])
+	offset += 4
+
+	salt = data[offset : offset+kdfSaltSize]
+	rest = data[offset+kdfSaltSize:]
+	return params, salt, rest, nil
+}
+
+// newFromPassphrase builds a passphrase-based Encrypter. The returned
+// Encrypter derives a fresh key (and salt) for every call to Encode using
+// aeadFromKey, and persists the KDF parameters and salt in the ciphertext so
+// Decode can re-derive the same key from the passphrase alone.
+func newFromPassphrase(passphrase []byte, params KDFParams, aeadFromKey func(key []byte) (cipher.AEAD, error), opts ...Option) (Encrypter, error) {
+	params = withKDFDefaults(params)
+	if err := validateKDFParams(params); err != nil {
+		return Encrypter{}, err
+	}
+	encrypter := Encrypter{
+		passphrase:  passphrase,
+		kdfParams:   params,
+		aeadFromKey: aeadFromKey,
+	}
+	for _, opt := range opts {
+		opt(&encrypter)
+	}
+	return encrypter, nil
+}
+
+// NewAESGCMFromPassphrase creates a new Encrypter that derives its AES-GCM
+// key from passphrase using the KDF selected by params, instead of requiring
+// callers to manage a raw 32-byte key. The KDF parameters and a random
+// per-message salt are persisted alongside the ciphertext so Decode can
+// recover the same key.
+//
+// Parameters:
+//   - passphrase []byte: The user-supplied passphrase the key is derived from.
+//   - params KDFParams: The KDF and its cost parameters. The zero value
+//     selects Argon2id with this package's default parameters.
+//   - opts ...Option: Optional settings, such as WithAADFunc.
+//
+// Returns:
+//   - Encrypter: An Encrypter configured to derive an AES-GCM key from
+//     passphrase on every Encode and Decode call.
+//   - error: ErrWeakKDFParams if params fall below the minimum this package
+//     considers safe.
+func NewAESGCMFromPassphrase(passphrase []byte, params KDFParams, opts ...Option) (Encrypter, error) {
+	return newFromPassphrase(passphrase, params, func(key []byte) (cipher.AEAD, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+		}
+		return cipher.NewGCMWithRandomNonce(block)
+	}, opts...)
+}
+
+// NewChaCha20Poly1305FromPassphrase creates a new Encrypter that derives its
+// ChaCha20-Poly1305 key from passphrase using the KDF selected by params,
+// instead of requiring callers to manage a raw 32-byte key. The KDF
+// parameters and a random per-message salt are persisted alongside the
+// ciphertext so Decode can recover the same key.
+//
+// Parameters:
+//   - passphrase []byte: The user-supplied passphrase the key is derived from.
+//   - params KDFParams: The KDF and its cost parameters. The zero value
+//     selects Argon2id with this package's default parameters.
+//   - opts ...Option: Optional settings, such as WithAADFunc.
+//
+// Returns:
+//   - Encrypter: An Encrypter configured to derive a ChaCha20-Poly1305 key
+//     from passphrase on every Encode and Decode call.
+//   - error: ErrWeakKDFParams if params fall below the minimum this package
+//     considers safe.
+func NewChaCha20Poly1305FromPassphrase(passphrase []byte, params KDFParams, opts ...Option) (Encrypter, error) {
+	return newFromPassphrase(passphrase, params, func(key []byte) (cipher.AEAD, error) {
+		return chacha20poly1305.New(key)
+	}, opts...)
+}
+
+// NewXChaCha20Poly1305FromPassphrase creates a new Encrypter that derives
+// its XChaCha20-Poly1305 key from passphrase using the KDF selected by
+// params, instead of requiring callers to manage a raw 32-byte key. The KDF
+// parameters and a random per-message salt are persisted alongside the
+// ciphertext so Decode can recover the same key.
+//
+// Parameters:
+//   - passphrase []byte: The user-supplied passphrase the key is derived from.
+//   - params KDFParams: The KDF and its cost parameters. The zero value
+//     selects Argon2id with this package's default parameters.
+//   - opts ...Option: Optional settings, such as WithAADFunc.
+//
+// Returns:
+//   - Encrypter: An Encrypter configured to derive an XChaCha20-Poly1305 key
+//     from passphrase on every Encode and Decode call.
+//   - error: ErrWeakKDFParams if params fall below the minimum this package
+//     considers safe.
+func NewXChaCha20Poly1305FromPassphrase(passphrase []byte, params KDFParams, opts ...Option) (Encrypter, error) {
+	return newFromPassphrase(passphrase, params, func(key []byte) (cipher.AEAD, error) {
+		return chacha20poly1305.NewX(key)
+	}, opts...)
+}
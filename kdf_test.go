@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testPassphrase = []byte("correct-horse-battery-staple")
+
+func TestEncrypterFromPassphrase(t *testing.T) {
+	tests := []struct {
+		name    string
+		newFunc func(passphrase []byte, params KDFParams, opts ...Option) (Encrypter, error)
+	}{
+		{"AES-GCM", NewAESGCMFromPassphrase},
+		{"ChaCha20-Poly1305", NewChaCha20Poly1305FromPassphrase},
+		{"XChaCha20-Poly1305", NewXChaCha20Poly1305FromPassphrase},
+	}
+	kdfs := []KDF{KDFArgon2id, KDFScrypt, KDFPBKDF2SHA256}
+	for _, tt := range tests {
+		for _, kdf := range kdfs {
+			t.Run(tt.name+"/"+kdfName(kdf), func(t *testing.T) {
+				encrypter, err := tt.newFunc(testPassphrase, KDFParams{KDF: kdf})
+				if err != nil {
+					t.Fatalf("failed to create encrypter: %s", err)
+				}
+				now := time.Now()
+				data := map[string]interface{}{"string": "test"}
+				ciphertext, err := encrypter.Encode(now, data)
+				if err != nil {
+					t.Fatalf("encryption failed: %s", err)
+				}
+				plaintime, plaindata, err := encrypter.Decode(ciphertext)
+				if err != nil {
+					t.Fatalf("decryption failed: %s", err)
+				}
+				if !plaintime.Equal(now) {
+					t.Errorf("expected plaintime to be %s, got %s", now, plaintime)
+				}
+				if value, ok := plaindata["string"]; !ok || value != data["string"] {
+					t.Errorf("expected decrypted string to be %s, got %s", data["string"], value)
+				}
+			})
+		}
+	}
+	t.Run("rejects weak kdf params", func(t *testing.T) {
+		_, err := NewAESGCMFromPassphrase(testPassphrase, KDFParams{KDF: KDFArgon2id, Time: 1, Memory: 1, Threads: 1})
+		if err == nil {
+			t.Fatalf("expected weak argon2id params to be rejected")
+		}
+		if !errors.Is(err, ErrWeakKDFParams) {
+			t.Errorf("expected ErrWeakKDFParams, got %s", err)
+		}
+	})
+	t.Run("fails with wrong passphrase", func(t *testing.T) {
+		encrypter, err := NewAESGCMFromPassphrase(testPassphrase, KDFParams{})
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := encrypter.Encode(time.Now(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+		wrong, err := NewAESGCMFromPassphrase([]byte("wrong passphrase"), KDFParams{})
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		if _, _, err = wrong.Decode(ciphertext); err == nil {
+			t.Errorf("expected decryption to fail with wrong passphrase")
+		}
+	})
+	t.Run("fails with missing kdf header", func(t *testing.T) {
+		encrypter, err := NewAESGCMFromPassphrase(testPassphrase, KDFParams{})
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		_, _, err = encrypter.Decode(make([]byte, kdfHeaderLen+16))
+		if !errors.Is(err, ErrInvalidKDFHeader) {
+			t.Errorf("expected ErrInvalidKDFHeader, got %s", err)
+		}
+	})
+	t.Run("applies options like a raw-key constructor", func(t *testing.T) {
+		contextAAD := func(time.Time, map[string]interface{}) []byte {
+			return []byte("session-token:abc123")
+		}
+		encrypter, err := NewAESGCMFromPassphrase(testPassphrase, KDFParams{}, WithAADFunc(contextAAD))
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := encrypter.Encode(time.Now(), map[string]interface{}{"string": "test"})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+		withoutAAD, err := NewAESGCMFromPassphrase(testPassphrase, KDFParams{})
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		if _, _, err = withoutAAD.Decode(ciphertext); err == nil {
+			t.Errorf("expected decryption to fail without the matching aad func")
+		}
+	})
+	t.Run("rejects tampered kdf header with oversized argon2id memory", func(t *testing.T) {
+		encrypter, err := NewAESGCMFromPassphrase(testPassphrase, KDFParams{})
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		ciphertext, err := encrypter.Encode(time.Now(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("encryption failed: %s", err)
+		}
+		// Memory follows magic(4) + version(1) + KDF(1) + Time(4) in the
+		// header; overwrite it to an attacker-supplied maximum to verify
+		// decrypt rejects it instead of deriving a key with it.
+		const memoryOffset = 4 + 1 + 1 + 4
+		binary.BigEndian.PutUint32(ciphertext[memoryOffset:], 0xffffffff)
+		if _, _, err = encrypter.Decode(ciphertext); !errors.Is(err, ErrInvalidKDFHeader) {
+			t.Errorf("expected ErrInvalidKDFHeader, got %s", err)
+		}
+	})
+}
+
+func kdfName(kdf KDF) string {
+	switch kdf {
+	case KDFScrypt:
+		return "scrypt"
+	case KDFPBKDF2SHA256:
+		return "pbkdf2-sha256"
+	default:
+		return "argon2id"
+	}
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+)
+
+// keyIDLen is the length, in bytes, of the key ID prepended to ciphertext
+// produced by a Keyset.
+const keyIDLen = 4
+
+// KeyID identifies one of the Encrypters registered in a Keyset. Encode
+// prepends the primary Encrypter's KeyID to the ciphertext so Decode can
+// later tell which key to use.
+type KeyID [keyIDLen]byte
+
+// DeriveKeyID computes the KeyID for a raw AEAD key as the first four bytes
+// of its SHA-256 digest. Encrypters created via NewAESGCM,
+// NewChaCha20Poly1305 or NewXChaCha20Poly1305 use this automatically; call
+// DeriveKeyID yourself to reproduce the same ID elsewhere, e.g. when
+// pre-computing the ID for a key that is about to be rotated in.
+func DeriveKeyID(key []byte) KeyID {
+	sum := sha256.Sum256(key)
+	var id KeyID
+	copy(id[:], sum[:keyIDLen])
+	return id
+}
+
+// ErrUnknownKeyID indicates that the key ID prepended to a ciphertext does
+// not match any Encrypter currently registered in the Keyset.
+var ErrUnknownKeyID = errors.New("unknown key id")
+
+// Keyset implements the SCS Codec interface over a primary Encrypter and any
+// number of secondary ones, enabling key rotation without invalidating
+// sessions that are still encrypted under a previous key. Encode always
+// seals with the primary Encrypter and prepends its KeyID to the
+// ciphertext; Decode reads that KeyID and dispatches to the matching
+// Encrypter, which may be a secondary one left over from before a
+// rotation. Keyset holds its mutable state behind a pointer, so a Keyset
+// value can be copied and shared freely; the zero value is not usable,
+// create one with NewKeyset.
+type Keyset struct {
+	state *keysetState
+}
+
+// keysetState is the mutable state shared by every copy of a Keyset value.
+type keysetState struct {
+	mu      sync.RWMutex
+	primary KeyID
+	keys    map[KeyID]Encrypter
+}
+
+// NewKeyset creates a Keyset whose primary key is primary and whose
+// secondary keys are secondary. Secondary keys are only used by Decode, to
+// accept ciphertext encrypted before a rotation; Encode always uses
+// primary. Each Encrypter's KeyID is derived with DeriveKeyID from the raw
+// key passed to NewAESGCM, NewChaCha20Poly1305 or NewXChaCha20Poly1305; an
+// Encrypter built with New or a *FromPassphrase constructor has no raw key
+// to derive from and is assigned a random KeyID instead, so register it
+// with AddKey using an explicit ID if it must survive a process restart.
+func NewKeyset(primary Encrypter, secondary ...Encrypter) Keyset {
+	state := &keysetState{keys: make(map[KeyID]Encrypter, 1+len(secondary))}
+	id := keyIDFor(primary)
+	state.primary = id
+	state.keys[id] = primary
+	for _, encrypter := range secondary {
+		state.keys[keyIDFor(encrypter)] = encrypter
+	}
+	return Keyset{state: state}
+}
+
+// keyIDFor returns the KeyID an Encrypter should be registered under: the
+// derived ID if it was built with a raw key, otherwise a random one.
+func keyIDFor(encrypter Encrypter) KeyID {
+	if len(encrypter.key) > 0 {
+		return DeriveKeyID(encrypter.key)
+	}
+	var id KeyID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+// AddKey registers encrypter under id, making it available to Decode. It
+// does not change the primary key Encode uses; call SetPrimary for that.
+// AddKey is safe to call concurrently with Encode and Decode, so it can be
+// driven directly from a config-reload path.
+func (ks Keyset) AddKey(id KeyID, encrypter Encrypter) {
+	ks.state.mu.Lock()
+	defer ks.state.mu.Unlock()
+	ks.state.keys[id] = encrypter
+}
+
+// RemoveKey deregisters the Encrypter stored under id. Removing the
+// current primary leaves Encode without a usable key until SetPrimary
+// registers a new one. RemoveKey is safe to call concurrently with Encode
+// and Decode.
+func (ks Keyset) RemoveKey(id KeyID) {
+	ks.state.mu.Lock()
+	defer ks.state.mu.Unlock()
+	delete(ks.state.keys, id)
+}
+
+// SetPrimary registers encrypter under id, if not already present, and
+// makes it the key Encode uses for new ciphertext. SetPrimary is safe to
+// call concurrently with Encode and Decode.
+func (ks Keyset) SetPrimary(id KeyID, encrypter Encrypter) {
+	ks.state.mu.Lock()
+	defer ks.state.mu.Unlock()
+	ks.state.keys[id] = encrypter
+	ks.state.primary = id
+}
+
+// Encode serializes and encrypts session data with the Keyset's primary
+// Encrypter, prepending its KeyID so Decode can identify the right key
+// later, including after a rotation.
+func (ks Keyset) Encode(deadline time.Time, values map[string]interface{}) ([]byte, error) {
+	ks.state.mu.RLock()
+	id := ks.state.primary
+	encrypter, ok := ks.state.keys[id]
+	ks.state.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	ciphertext, err := encrypter.Encode(deadline, values)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, keyIDLen+len(ciphertext))
+	out = append(out, id[:]...)
+	out = append(out, ciphertext...)
+	return out, nil
+}
+
+// Decode reads the KeyID prepended to ciphertext and decrypts it with the
+// matching Encrypter, returning ErrUnknownKeyID if no registered key
+// matches.
+func (ks Keyset) Decode(ciphertext []byte) (time.Time, map[string]interface{}, error) {
+	if len(ciphertext) < keyIDLen {
+		return time.Time{}, nil, ErrCiphertextTooShort
+	}
+	var id KeyID
+	copy(id[:], ciphertext[:keyIDLen])
+
+	ks.state.mu.RLock()
+	encrypter, ok := ks.state.keys[id]
+	ks.state.mu.RUnlock()
+	if !ok {
+		return time.Time{}, nil, ErrUnknownKeyID
+	}
+	return encrypter.Decode(ciphertext[keyIDLen:])
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+package scscrypter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKeyset_EncodeDecode(t *testing.T) {
+	oldEncrypter, err := NewAESGCM(testKey256)
+	if err != nil {
+		t.Fatalf("failed to create old encrypter: %s", err)
+	}
+
+	ks := NewKeyset(oldEncrypter)
+	ciphertext, err := ks.Encode(time.Now(), map[string]interface{}{"v": 1})
+	if err != nil {
+		t.Fatalf("failed to encode: %s", err)
+	}
+	if _, _, err = ks.Decode(ciphertext); err != nil {
+		t.Fatalf("failed to decode with primary key: %s", err)
+	}
+	oldCiphertext := ciphertext
+
+	t.Run("rotates primary and still decodes old ciphertext", func(t *testing.T) {
+		newEncrypter, err := NewAESGCM(testKey128)
+		if err != nil {
+			t.Fatalf("failed to create new encrypter: %s", err)
+		}
+		ks.SetPrimary(DeriveKeyID(testKey128), newEncrypter)
+
+		rotated, err := ks.Encode(time.Now(), map[string]interface{}{"v": 2})
+		if err != nil {
+			t.Fatalf("failed to encode after rotation: %s", err)
+		}
+		if _, _, err = ks.Decode(rotated); err != nil {
+			t.Errorf("failed to decode ciphertext encrypted under the new primary: %s", err)
+		}
+		if _, _, err = ks.Decode(oldCiphertext); err != nil {
+			t.Errorf("expected ciphertext from the retired primary to still decode: %s", err)
+		}
+	})
+
+	t.Run("fails with unknown key id", func(t *testing.T) {
+		unknown := KeyID{0xff, 0xff, 0xff, 0xff}
+		_, _, err := ks.Decode(append(unknown[:], ciphertext[keyIDLen:]...))
+		if !errors.Is(err, ErrUnknownKeyID) {
+			t.Errorf("expected ErrUnknownKeyID, got %s", err)
+		}
+	})
+
+	t.Run("fails with too short ciphertext", func(t *testing.T) {
+		_, _, err := ks.Decode([]byte{0x01})
+		if !errors.Is(err, ErrCiphertextTooShort) {
+			t.Errorf("expected ErrCiphertextTooShort, got %s", err)
+		}
+	})
+
+	t.Run("RemoveKey invalidates its ciphertext", func(t *testing.T) {
+		removed, err := NewAESGCM(testKey256)
+		if err != nil {
+			t.Fatalf("failed to create encrypter: %s", err)
+		}
+		id := DeriveKeyID(testKey256)
+		ks.AddKey(id, removed)
+		encoded, err := removed.Encode(time.Now(), map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("failed to encode: %s", err)
+		}
+		wrapped := append(id[:], encoded...)
+		if _, _, err = ks.Decode(wrapped); err != nil {
+			t.Fatalf("expected decode to succeed before removal: %s", err)
+		}
+		ks.RemoveKey(id)
+		if _, _, err = ks.Decode(wrapped); !errors.Is(err, ErrUnknownKeyID) {
+			t.Errorf("expected ErrUnknownKeyID after RemoveKey, got %s", err)
+		}
+	})
+}
@@ -8,11 +8,9 @@
 package scscrypter
 
 import (
-	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"encoding/gob"
 	"errors"
 	"fmt"
 	"io"
@@ -37,7 +35,24 @@ var (
 // Fields:
 //   - cipher (cipher.AEAD): An AEAD cipher used to perform the encryption
 //     and decryption. The cipher must be initialized before use, and it provides
-//     both confidentiality and authenticity for the data.
+//     both confidentiality and authenticity for the data. Unset for an
+//     Encrypter created by one of the *FromPassphrase constructors.
+//   - passphrase, kdfParams, aeadFromKey: Set by the *FromPassphrase
+//     constructors instead of cipher. Encode derives a fresh key (and salt)
+//     from passphrase for every call via aeadFromKey, and Decode re-derives
+//     it from the KDF header persisted alongside the ciphertext.
+//   - key ([]byte): The raw key passed to NewAESGCM, NewChaCha20Poly1305 or
+//     NewXChaCha20Poly1305, retained only so a Keyset can derive a stable
+//     key ID for rotation. Unset for Encrypters built with New or a
+//     *FromPassphrase constructor.
+//   - aadFunc (AADFunc): Set via WithAADFunc. Binds extra caller-supplied
+//     context into the AEAD's additional authenticated data, alongside the
+//     deadline this package always binds by default.
+//   - codec (ValueCodec): Set via NewWithCodec. Serializes the deadline and
+//     values Encode and Decode operate on. Defaults to GobValueCodec.
+//   - compressionAlgo, compressionLevel: Set via WithCompression. Compress
+//     the serialized session data before encryption if doing so makes it
+//     smaller. Unset (CompressionAlgo zero value) disables compression.
 //
 // Usage:
 // The Encrypter struct is designed for encrypting sensitive data that needs
@@ -45,6 +60,17 @@ var (
 // that handle encoding and decoding, making it suitable for complex data structures.
 type Encrypter struct {
 	cipher cipher.AEAD
+	key    []byte
+
+	passphrase  []byte
+	kdfParams   KDFParams
+	aeadFromKey func(key []byte) (cipher.AEAD, error)
+
+	aadFunc AADFunc
+	codec   ValueCodec
+
+	compressionAlgo  CompressionAlgo
+	compressionLevel int
 }
 
 // New creates a new Encrypter instance using the provided AEAD cipher for
@@ -55,14 +81,19 @@ type Encrypter struct {
 // Parameters:
 //   - aead cipher.AEAD: The AEAD cipher used for encryption and decryption.
 //     This cipher must be properly initialized before calling New.
+//   - opts ...Option: Optional settings, such as WithAADFunc.
 //
 // Returns:
 //   - Encrypter: An Encrypter instance configured with the given AEAD cipher,
 //     ready to perform encryption and decryption operations.
-func New(aead cipher.AEAD) Encrypter {
-	return Encrypter{
+func New(aead cipher.AEAD, opts ...Option) Encrypter {
+	encrypter := Encrypter{
 		cipher: aead,
 	}
+	for _, opt := range opts {
+		opt(&encrypter)
+	}
+	return encrypter
 }
 
 // NewAESGCM creates a new Encrypter instance using AES-GCM mode with the
@@ -73,12 +104,13 @@ func New(aead cipher.AEAD) Encrypter {
 // Parameters:
 //   - key []byte: The encryption key used to initialize the AES cipher.
 //     The key must be a valid length for AES (e.g., 16, 24, or 32 bytes).
+//   - opts ...Option: Optional settings, such as WithAADFunc.
 //
 // Returns:
 //   - Encrypter: An Encrypter instance configured with AES-GCM for encryption
 //     and decryption operations.
 //   - error: An error if the cipher creation or AEAD initialization fails.
-func NewAESGCM(key []byte) (Encrypter, error) {
+func NewAESGCM(key []byte, opts ...Option) (Encrypter, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return Encrypter{}, fmt.Errorf("failed to create AES cipher: %w", err)
@@ -87,7 +119,9 @@ func NewAESGCM(key []byte) (Encrypter, error) {
 	if err != nil {
 		return Encrypter{}, fmt.Errorf("failed to create AES-GCM AEAD: %w", err)
 	}
-	return New(aead), nil
+	encrypter := New(aead, opts...)
+	encrypter.key = key
+	return encrypter, nil
 }
 
 // NewChaCha20Poly1305 creates a new Encrypter instance using the ChaCha20-Poly1305
@@ -98,17 +132,20 @@ func NewAESGCM(key []byte) (Encrypter, error) {
 // Parameters:
 //   - key []byte: The encryption key used to initialize the ChaCha20-Poly1305 cipher.
 //     The key must be exactly 32 bytes in length.
+//   - opts ...Option: Optional settings, such as WithAADFunc.
 //
 // Returns:
 //   - Encrypter: An Encrypter instance configured with ChaCha20-Poly1305 for encryption
 //     and decryption operations.
 //   - error: An error if the AEAD initialization fails.
-func NewChaCha20Poly1305(key []byte) (Encrypter, error) {
+func NewChaCha20Poly1305(key []byte, opts ...Option) (Encrypter, error) {
 	aead, err := chacha20poly1305.New(key)
 	if err != nil {
 		return Encrypter{}, fmt.Errorf("failed to create ChaCha20-Poly1305 AEAD: %w", err)
 	}
-	return New(aead), nil
+	encrypter := New(aead, opts...)
+	encrypter.key = key
+	return encrypter, nil
 }
 
 // NewXChaCha20Poly1305 creates a new Encrypter instance using the XChaCha20-Poly1305
@@ -119,17 +156,20 @@ func NewChaCha20Poly1305(key []byte) (Encrypter, error) {
 // Parameters:
 //   - key []byte: The encryption key used to initialize the XChaCha20-Poly1305 cipher.
 //     The key must be exactly 32 bytes in length.
+//   - opts ...Option: Optional settings, such as WithAADFunc.
 //
 // Returns:
 //   - Encrypter: An Encrypter instance configured with XChaCha20-Poly1305 for encryption
 //     and decryption operations.
 //   - error: An error if the AEAD initialization fails.
-func NewXChaCha20Poly1305(key []byte) (Encrypter, error) {
+func NewXChaCha20Poly1305(key []byte, opts ...Option) (Encrypter, error) {
 	aead, err := chacha20poly1305.NewX(key)
 	if err != nil {
 		return Encrypter{}, fmt.Errorf("failed to create ChaCha20-Poly1305 AEAD: %w", err)
 	}
-	return New(aead), nil
+	encrypter := New(aead, opts...)
+	encrypter.key = key
+	return encrypter, nil
 }
 
 // Encode serializes and encrypts session data, ensuring secure storage.
@@ -142,23 +182,38 @@ func NewXChaCha20Poly1305(key []byte) (Encrypter, error) {
 //   - []byte: The encrypted session data.
 //   - error: An error if encoding or encryption fails.
 //
-// The function first serializes the input data using gob encoding, then encrypts it using
-// the underlying iocrypter encryption mechanism.
+// The function first serializes the input data using e's ValueCodec
+// (GobValueCodec by default), prefixes it with a 1-byte codec tag so
+// Decode can auto-select the matching codec, optionally compresses it if
+// WithCompression was configured, then encrypts it using the underlying
+// AEAD cipher.
 func (e Encrypter) Encode(deadline time.Time, values map[string]interface{}) ([]byte, error) {
-	aux := &struct {
-		Deadline time.Time
-		Values   map[string]interface{}
-	}{
-		Deadline: deadline,
-		Values:   values,
+	codec := e.codec
+	if codec == nil {
+		codec = GobValueCodec{}
 	}
+	tag, err := tagForCodec(codec)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := codec.Marshal(deadline, values)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, 1+len(payload))
+	data = append(data, byte(tag))
+	data = append(data, payload...)
 
-	buffer := bytes.NewBuffer(nil)
-	if err := gob.NewEncoder(buffer).Encode(aux); err != nil {
-		return nil, fmt.Errorf("failed to encode session data: %w", err)
+	compressionTag, compressed, err := compress(e.compressionAlgo, e.compressionLevel, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress session data: %w", err)
 	}
+	data = make([]byte, 0, 1+len(compressed))
+	data = append(data, byte(compressionTag))
+	data = append(data, compressed...)
 
-	return e.encrypt(buffer.Bytes())
+	return e.encrypt(data, deadline, values)
 }
 
 // Decode decrypts and deserializes session data, restoring the original values.
@@ -171,47 +226,133 @@ func (e Encrypter) Encode(deadline time.Time, values map[string]interface{}) ([]
 //   - map[string]interface{}: The restored session values.
 //   - error: An error if decryption or decoding fails.
 //
-// The function decrypts the given ciphertext using iocrypter and deserializes it back
-// into its structured session representation.
+// The function decrypts the given ciphertext using the underlying AEAD
+// cipher, reads the 1-byte compression tag Encode prefixed the plaintext
+// with to decompress it if needed, then reads the 1-byte codec tag that
+// follows to select the matching ValueCodec and deserialize it back into
+// its structured session representation.
 func (e Encrypter) Decode(ciphertext []byte) (time.Time, map[string]interface{}, error) {
-	aux := &struct {
-		Deadline time.Time
-		Values   map[string]interface{}
-	}{}
-
 	data, err := e.decrypt(ciphertext)
 	if err != nil {
 		return time.Time{}, nil, fmt.Errorf("failed to decrypt session data: %w", err)
 	}
-	decrypter := bytes.NewReader(data)
-	if err = gob.NewDecoder(decrypter).Decode(&aux); err != nil {
+	if len(data) < 1 {
+		return time.Time{}, nil, ErrCiphertextTooShort
+	}
+
+	data, err = decompress(compressionTag(data[0]), data[1:])
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("failed to decompress session data: %w", err)
+	}
+	if len(data) < 1 {
+		return time.Time{}, nil, ErrCiphertextTooShort
+	}
+
+	codec, err := codecForTag(codecTag(data[0]))
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	deadline, values, err := codec.Unmarshal(data[1:])
+	if err != nil {
 		return time.Time{}, nil, fmt.Errorf("failed to decode session data: %w", err)
 	}
 
-	return aux.Deadline, aux.Values, nil
+	return deadline, values, nil
 }
 
-// encrypt is the underlying encryption method
-func (e Encrypter) encrypt(data []byte) ([]byte, error) {
-	if e.cipher == nil {
+// encrypt is the underlying encryption method. deadline and values are not
+// encrypted here directly; deadline is bound into the AEAD's additional
+// authenticated data (see buildAAD), and values is passed through to
+// aadFunc, if one is configured.
+func (e Encrypter) encrypt(data []byte, deadline time.Time, values map[string]interface{}) ([]byte, error) {
+	aead := e.cipher
+	var kdfHeader []byte
+	if e.aeadFromKey != nil {
+		salt := make([]byte, kdfSaltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, fmt.Errorf("failed to generate random salt: %w", err)
+		}
+		key, err := deriveKey(e.kdfParams, e.passphrase, salt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key from passphrase: %w", err)
+		}
+		aead, err = e.aeadFromKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize AEAD cipher: %w", err)
+		}
+		kdfHeader = encodeKDFHeader(e.kdfParams, salt)
+	}
+	if aead == nil {
 		return nil, ErrNoCipher
 	}
-	nonce := make([]byte, e.cipher.NonceSize())
+
+	deadlineHeader := encodeDeadlineHeader(deadline)
+	aad := e.buildAAD(deadlineHeader, deadline, values)
+
+	nonce := make([]byte, aead.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, fmt.Errorf("failed to generate random iv: %w", err)
 	}
-	cipherText := e.cipher.Seal(nonce, nonce, data, nil)
-	return cipherText, nil
+	cipherText := aead.Seal(nonce, nonce, data, aad)
+
+	out := append(kdfHeader, deadlineHeader...)
+	return append(out, cipherText...), nil
 }
 
-// decrypt is the underlying decyption method
+// decrypt is the underlying decryption method. It recovers the deadline
+// bound into the ciphertext's additional authenticated data from the
+// clear-text header Encode prepends, so it can reconstruct the same AAD
+// for Open without first having to decrypt anything.
 func (e Encrypter) decrypt(data []byte) ([]byte, error) {
-	if e.cipher == nil {
+	aead := e.cipher
+	if e.aeadFromKey != nil {
+		params, salt, rest, err := decodeKDFHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateKDFParams(params); err != nil {
+			return nil, ErrInvalidKDFHeader
+		}
+		key, err := deriveKey(params, e.passphrase, salt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key from passphrase: %w", err)
+		}
+		aead, err = e.aeadFromKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize AEAD cipher: %w", err)
+		}
+		data = rest
+	}
+	if aead == nil {
 		return nil, ErrNoCipher
 	}
-	if len(data) < e.cipher.NonceSize() {
+	if len(data) < deadlineAADLen {
 		return nil, ErrCiphertextTooShort
 	}
-	nonce, ciphertext := data[:e.cipher.NonceSize()], data[e.cipher.NonceSize():]
-	return e.cipher.Open(nil, nonce, ciphertext, nil)
+	deadlineHeader, rest := data[:deadlineAADLen], data[deadlineAADLen:]
+	deadline := decodeDeadlineHeader(deadlineHeader)
+	aad := e.buildAAD(deadlineHeader, deadline, nil)
+
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrCiphertextTooShort
+	}
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
+
+// buildAAD assembles the additional authenticated data bound into every
+// ciphertext:
+//
+//	AAD = deadlineHeader (8 bytes, big-endian Unix seconds) || aadFunc(deadline, values)
+//
+// The deadline header is always bound, even if e.aadFunc is nil, so a
+// ciphertext cannot be swapped for another one carrying a longer-lived
+// deadline without failing authentication. This layout is part of the
+// on-wire format and must not change without a version bump.
+func (e Encrypter) buildAAD(deadlineHeader []byte, deadline time.Time, values map[string]interface{}) []byte {
+	aad := append([]byte(nil), deadlineHeader...)
+	if e.aadFunc != nil {
+		aad = append(aad, e.aadFunc(deadline, values)...)
+	}
+	return aad
 }
@@ -27,7 +27,7 @@ func TestEncryptor_New(t *testing.T) {
 	tests := []struct {
 		name    string
 		key     []byte
-		newFunc func(key []byte) (Encrypter, error)
+		newFunc func(key []byte, opts ...Option) (Encrypter, error)
 	}{
 		{"AES-256-GCM", testKey256, NewAESGCM},
 		{"AES-128-GCM", testKey128, NewAESGCM},
@@ -98,7 +98,7 @@ func TestEncrypter_Encode(t *testing.T) {
 	tests := []struct {
 		name    string
 		key     []byte
-		newFunc func(key []byte) (Encrypter, error)
+		newFunc func(key []byte, opts ...Option) (Encrypter, error)
 	}{
 		{"AES-256-GCM", testKey256, NewAESGCM},
 		{"AES-128-GCM", testKey128, NewAESGCM},
@@ -163,7 +163,7 @@ func TestEncrypter_Decode(t *testing.T) {
 	tests := []struct {
 		name    string
 		key     []byte
-		newFunc func(key []byte) (Encrypter, error)
+		newFunc func(key []byte, opts ...Option) (Encrypter, error)
 	}{
 		{"AES-256-GCM", testKey256, NewAESGCM},
 		{"AES-128-GCM", testKey128, NewAESGCM},
@@ -190,7 +190,8 @@ func TestEncrypter_Decode(t *testing.T) {
 			if err != nil {
 				t.Fatalf("failed to create encryptor: %s", err)
 			}
-			ciphertext, err := encrypter.encrypt([]byte("foobar"))
+			plaintext := append([]byte{byte(compressionTagNone), byte(codecTagGob)}, "foobar"...)
+			ciphertext, err := encrypter.encrypt(plaintext, time.Now(), nil)
 			if err != nil {
 				t.Fatalf("encryption failed: %s", err)
 			}
@@ -206,7 +207,7 @@ func TestEncrypter_encrypt(t *testing.T) {
 	tests := []struct {
 		name                string
 		key                 []byte
-		newFunc             func(key []byte) (Encrypter, error)
+		newFunc             func(key []byte, opts ...Option) (Encrypter, error)
 		skipRandReaderCheck bool
 	}{
 		{"AES-256-GCM", testKey256, NewAESGCM, true},
@@ -226,7 +227,7 @@ func TestEncrypter_encrypt(t *testing.T) {
 			defaultRandReader := rand.Reader
 			t.Cleanup(func() { rand.Reader = defaultRandReader })
 			rand.Reader = &failReader{}
-			_, err = encryptor.encrypt([]byte("test data"))
+			_, err = encryptor.encrypt([]byte("test data"), time.Now(), nil)
 			if err == nil {
 				t.Fatalf("expected encryption to fail")
 			}
@@ -241,7 +242,7 @@ func TestEncrypter_decrypt(t *testing.T) {
 	tests := []struct {
 		name           string
 		key            []byte
-		newFunc        func(key []byte) (Encrypter, error)
+		newFunc        func(key []byte, opts ...Option) (Encrypter, error)
 		skipShortCheck bool
 	}{
 		{"AES-256-GCM", testKey256, NewAESGCM, true},
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: Winni Neessen <wn@neessen.dev>
+//
+// SPDX-License-Identifier: MIT
+
+//go:build wycheproof
+
+package scscrypter
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// aeadTestVector mirrors a single Project Wycheproof AEAD test vector.
+type aeadTestVector struct {
+	TcID    int    `json:"tcId"`
+	Comment string `json:"comment"`
+	Key     string `json:"key"`
+	IV      string `json:"iv"`
+	AAD     string `json:"aad"`
+	Msg     string `json:"msg"`
+	CT      string `json:"ct"`
+	Tag     string `json:"tag"`
+	Result  string `json:"result"`
+}
+
+// aeadTestGroup mirrors a Project Wycheproof AeadTestGroup.
+type aeadTestGroup struct {
+	IVSize  int              `json:"ivSize"`
+	KeySize int              `json:"keySize"`
+	TagSize int              `json:"tagSize"`
+	Tests   []aeadTestVector `json:"tests"`
+}
+
+// aeadTestFile mirrors the top-level structure of a Wycheproof AEAD JSON
+// file.
+type aeadTestFile struct {
+	Algorithm  string          `json:"algorithm"`
+	TestGroups []aeadTestGroup `json:"testGroups"`
+}
+
+// loadAeadTestFile reads and parses a vendored Wycheproof JSON file from
+// testdata/wycheproof. See testdata/wycheproof/NOTICE for provenance: these
+// are a trimmed, pinned subset of the upstream testvectors_v1 files,
+// restricted to the IV size this package's AEAD backends actually support
+// (96 bits for AES-GCM/ChaCha20-Poly1305, 192 bits for
+// XChaCha20-Poly1305) and sampled down to a handful of vectors per
+// category; the fields of each kept vector are as published.
+func loadAeadTestFile(t *testing.T, name string) aeadTestFile {
+	t.Helper()
+	data, err := os.ReadFile("testdata/wycheproof/" + name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", name, err)
+	}
+	var file aeadTestFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("failed to parse %s: %s", name, err)
+	}
+	return file
+}
+
+// runAeadTestFile constructs an AEAD from each vector's key via newAEAD and
+// asserts that Open behaves as the vector's result field expects: it must
+// succeed for "valid", fail for "invalid", and is logged, not asserted, for
+// "acceptable" since those vectors are implementation-defined.
+func runAeadTestFile(t *testing.T, name string, newAEAD func(key []byte) (cipher.AEAD, error)) {
+	t.Helper()
+	file := loadAeadTestFile(t, name)
+	for _, group := range file.TestGroups {
+		for _, vector := range group.Tests {
+			t.Run(vector.Comment, func(t *testing.T) {
+				key := mustHex(t, vector.Key)
+				iv := mustHex(t, vector.IV)
+				aad := mustHex(t, vector.AAD)
+				msg := mustHex(t, vector.Msg)
+				ct := mustHex(t, vector.CT)
+				tag := mustHex(t, vector.Tag)
+
+				aead, err := newAEAD(key)
+				if err != nil {
+					if vector.Result == "valid" {
+						t.Fatalf("failed to construct AEAD for a valid vector: %s", err)
+					}
+					return
+				}
+				if len(iv) != aead.NonceSize() {
+					if vector.Result == "valid" {
+						t.Fatalf("vector iv length %d does not match AEAD nonce size %d", len(iv), aead.NonceSize())
+					}
+					return
+				}
+
+				sealed := append(append([]byte(nil), ct...), tag...)
+				opened, err := aead.Open(nil, iv, sealed, aad)
+
+				switch vector.Result {
+				case "valid":
+					if err != nil {
+						t.Fatalf("expected vector to decrypt successfully, got: %s", err)
+					}
+					if !bytes.Equal(opened, msg) {
+						t.Fatalf("decrypted message does not match expected plaintext")
+					}
+				case "invalid":
+					if err == nil {
+						t.Fatalf("expected vector to fail authentication, but it decrypted successfully")
+					}
+				case "acceptable":
+					t.Logf("acceptable vector tcId=%d: open error=%v", vector.TcID, err)
+				default:
+					t.Fatalf("unknown result %q for tcId=%d", vector.Result, vector.TcID)
+				}
+			})
+		}
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("failed to decode hex %q: %s", s, err)
+	}
+	return b
+}
+
+func TestWycheproofAESGCM(t *testing.T) {
+	runAeadTestFile(t, "aes_gcm_test.json", func(key []byte) (cipher.AEAD, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	})
+}
+
+func TestWycheproofChaCha20Poly1305(t *testing.T) {
+	runAeadTestFile(t, "chacha20_poly1305_test.json", chacha20poly1305.New)
+}
+
+func TestWycheproofXChaCha20Poly1305(t *testing.T) {
+	runAeadTestFile(t, "xchacha20_poly1305_test.json", chacha20poly1305.NewX)
+}